@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// AlertMessage is the provider-agnostic payload fanned out to every configured Alerter.
+type AlertMessage struct {
+	Title     string
+	Text      string
+	Severity  string // "info", "warning", "critical"
+	Timestamp time.Time
+}
+
+// Alerter is implemented by every notification sink (Telegram, Slack, Discord, ...).
+type Alerter interface {
+	Send(ctx context.Context, msg AlertMessage) error
+}
+
+// TelegramProvider sends alerts through the existing Telegram bot API.
+type TelegramProvider struct {
+	BotToken string
+	ChatID   string
+}
+
+func (p *TelegramProvider) Send(ctx context.Context, msg AlertMessage) error {
+	text := msg.Text
+	if msg.Title != "" {
+		text = fmt.Sprintf("<b>%s</b>\n%s", msg.Title, msg.Text)
+	}
+	return sendTelegramMessage(p.BotToken, p.ChatID, text)
+}
+
+// SlackProvider posts to a Slack incoming webhook.
+type SlackProvider struct {
+	WebhookURL string
+}
+
+func (p *SlackProvider) Send(ctx context.Context, msg AlertMessage) error {
+	text := msg.Text
+	if msg.Title != "" {
+		text = fmt.Sprintf("*%s*\n%s", msg.Title, msg.Text)
+	}
+	return postJSON(ctx, p.WebhookURL, map[string]string{"text": text}, nil)
+}
+
+// DiscordProvider posts to a Discord webhook.
+type DiscordProvider struct {
+	WebhookURL string
+}
+
+func (p *DiscordProvider) Send(ctx context.Context, msg AlertMessage) error {
+	content := msg.Text
+	if msg.Title != "" {
+		content = fmt.Sprintf("**%s**\n%s", msg.Title, msg.Text)
+	}
+	return postJSON(ctx, p.WebhookURL, map[string]string{"content": content}, nil)
+}
+
+// GenericWebhookProvider POSTs the alert as JSON to an arbitrary URL with configurable headers.
+type GenericWebhookProvider struct {
+	URL     string
+	Headers map[string]string
+}
+
+func (p *GenericWebhookProvider) Send(ctx context.Context, msg AlertMessage) error {
+	body := map[string]interface{}{
+		"title":     msg.Title,
+		"text":      msg.Text,
+		"severity":  msg.Severity,
+		"timestamp": msg.Timestamp,
+	}
+	return postJSON(ctx, p.URL, body, p.Headers)
+}
+
+// PagerDutyProvider triggers an Events API v2 event.
+type PagerDutyProvider struct {
+	RoutingKey string
+}
+
+func (p *PagerDutyProvider) Send(ctx context.Context, msg AlertMessage) error {
+	severity := msg.Severity
+	if severity == "" {
+		severity = "info"
+	}
+	body := map[string]interface{}{
+		"routing_key":  p.RoutingKey,
+		"event_action": "trigger",
+		"payload": map[string]interface{}{
+			"summary":   firstNonEmpty(msg.Title, msg.Text),
+			"source":    "lab-cloudrun-monitor",
+			"severity":  severity,
+			"timestamp": msg.Timestamp,
+			"custom_details": map[string]string{
+				"text": msg.Text,
+			},
+		},
+	}
+	return postJSON(ctx, "https://events.pagerduty.com/v2/enqueue", body, nil)
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// postJSON marshals body and POSTs it to url, returning an error on non-2xx responses.
+func postJSON(ctx context.Context, url string, body interface{}, headers map[string]string) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+// MultiAlerter fans an alert out to every configured provider concurrently. A provider
+// that fails or times out does not prevent the others from delivering the message.
+type MultiAlerter struct {
+	Providers       []Alerter
+	ProviderTimeout time.Duration
+}
+
+func (m *MultiAlerter) Send(ctx context.Context, msg AlertMessage) error {
+	timeout := m.ProviderTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	errs := make(chan error, len(m.Providers))
+	for _, provider := range m.Providers {
+		provider := provider
+		go func() {
+			pctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			errs <- provider.Send(pctx, msg)
+		}()
+	}
+
+	var failures []string
+	for range m.Providers {
+		if err := <-errs; err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%d/%d alerters failed: %s", len(failures), len(m.Providers), strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// loadAlertersFromEnv builds the list of enabled Alerter providers from ALERT_PROVIDERS,
+// a comma-separated list such as "telegram,slack,webhook". Each provider reads its own
+// credentials from env and is skipped (with a log line) if those are missing.
+func loadAlertersFromEnv() []Alerter {
+	raw := os.Getenv("ALERT_PROVIDERS")
+	if raw == "" {
+		return nil
+	}
+
+	var providers []Alerter
+	for _, name := range strings.Split(raw, ",") {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "telegram":
+			botToken, chatID, ok := getTelegramEnv()
+			if !ok {
+				fmt.Println("[Alerter] telegram enabled but BOT_TOKEN/CHAT_ID missing, skipping")
+				continue
+			}
+			providers = append(providers, &TelegramProvider{BotToken: botToken, ChatID: chatID})
+		case "slack":
+			url := os.Getenv("SLACK_WEBHOOK_URL")
+			if url == "" {
+				fmt.Println("[Alerter] slack enabled but SLACK_WEBHOOK_URL missing, skipping")
+				continue
+			}
+			providers = append(providers, &SlackProvider{WebhookURL: url})
+		case "discord":
+			url := os.Getenv("DISCORD_WEBHOOK_URL")
+			if url == "" {
+				fmt.Println("[Alerter] discord enabled but DISCORD_WEBHOOK_URL missing, skipping")
+				continue
+			}
+			providers = append(providers, &DiscordProvider{WebhookURL: url})
+		case "webhook":
+			url := os.Getenv("WEBHOOK_URL")
+			if url == "" {
+				fmt.Println("[Alerter] webhook enabled but WEBHOOK_URL missing, skipping")
+				continue
+			}
+			providers = append(providers, &GenericWebhookProvider{URL: url, Headers: parseHeaderList(os.Getenv("WEBHOOK_HEADERS"))})
+		case "pagerduty":
+			key := os.Getenv("PAGERDUTY_ROUTING_KEY")
+			if key == "" {
+				fmt.Println("[Alerter] pagerduty enabled but PAGERDUTY_ROUTING_KEY missing, skipping")
+				continue
+			}
+			providers = append(providers, &PagerDutyProvider{RoutingKey: key})
+		default:
+			fmt.Printf("[Alerter] unknown provider %q, skipping\n", name)
+		}
+	}
+	return providers
+}
+
+// parseHeaderList parses a "Key:Value,Key2:Value2" list into a header map.
+func parseHeaderList(raw string) map[string]string {
+	if raw == "" {
+		return nil
+	}
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return headers
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseHeaderList(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want map[string]string
+	}{
+		{"empty", "", nil},
+		{"single", "X-Api-Key:abc123", map[string]string{"X-Api-Key": "abc123"}},
+		{
+			"multiple with spaces",
+			"X-Api-Key: abc123, Authorization : Bearer xyz",
+			map[string]string{"X-Api-Key": "abc123", "Authorization": "Bearer xyz"},
+		},
+		{"malformed pair is skipped", "X-Api-Key:abc123,no-colon-here", map[string]string{"X-Api-Key": "abc123"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseHeaderList(tt.raw)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseHeaderList(%q) = %#v, want %#v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// statsAPIResponse is the JSON payload returned by /api/stats.
+type statsAPIResponse struct {
+	Bucket  string     `json:"bucket"`
+	Buckets []Bucketed `json:"buckets"`
+}
+
+// statsAPIHandler serves GET /api/stats?from=RFC3339&to=RFC3339&bucket=1h.
+// from/to default to the last 24h and bucket defaults to 1h.
+func statsAPIHandler(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketParam := r.URL.Query().Get("bucket")
+		if bucketParam == "" {
+			bucketParam = "1h"
+		}
+		bucket, err := time.ParseDuration(bucketParam)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid bucket %q: %v", bucketParam, err), http.StatusBadRequest)
+			return
+		}
+
+		to := time.Now()
+		from := to.Add(-24 * time.Hour)
+		if parsedFrom, parsedTo, ok := parseRange(r); ok {
+			from, to = parsedFrom, parsedTo
+		}
+
+		buckets, err := store.AggregateRange(r.Context(), from, to, bucket)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to aggregate stats: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(statsAPIResponse{Bucket: bucketParam, Buckets: buckets})
+	}
+}
+
+// parseRange reads the "from" and "to" RFC3339 query params, if both are present.
+func parseRange(r *http.Request) (from, to time.Time, ok bool) {
+	fromRaw := r.URL.Query().Get("from")
+	toRaw := r.URL.Query().Get("to")
+	if fromRaw == "" || toRaw == "" {
+		return time.Time{}, time.Time{}, false
+	}
+
+	from, err := time.Parse(time.RFC3339, fromRaw)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	to, err = time.Parse(time.RFC3339, toRaw)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// StartStatsAPI registers /api/stats on the default mux and serves it on STATS_API_ADDR
+// (default ":8080"). It is safe to call alongside other handlers registered on the
+// default mux by the caller's main().
+func StartStatsAPI(store Store) {
+	addr := os.Getenv("STATS_API_ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+
+	http.HandleFunc("/api/stats", statsAPIHandler(store))
+	go func() {
+		if err := http.ListenAndServe(addr, nil); err != nil {
+			fmt.Printf("[API] stats server stopped: %v\n", err)
+		}
+	}()
+	fmt.Println("[API] /api/stats listening on", addr)
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/color"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+// chartKind selects which chart renderTraffic/renderInbound/renderUsers method a
+// chartJob is routed to. The zero value (chartKindTraffic) is the original uplink/downlink
+// line chart, so existing callers that never set Kind keep working unchanged.
+type chartKind string
+
+const (
+	chartKindTraffic chartKind = ""
+	chartKindInbound chartKind = "inbound"
+	chartKindUsers   chartKind = "users"
+)
+
+// chartUserTopN is how many users the "users" chart keeps; the rest are dropped rather
+// than cluttering the bar chart.
+const chartUserTopN = 10
+
+// chartJob describes a single chart render-and-send request.
+type chartJob struct {
+	ctx     context.Context
+	chatID  string
+	kind    chartKind
+	window  time.Duration
+	bucket  time.Duration
+	caption string
+}
+
+// chartWorker renders traffic charts off the hot path. Renders run one at a time from a
+// bounded queue so a burst of /chart requests can't pile up goroutines or memory.
+type chartWorker struct {
+	client *TelegramClient
+	store  Store
+	queue  chan chartJob
+}
+
+// NewChartWorker starts a single background renderer reading from a queue of size queueSize.
+func NewChartWorker(client *TelegramClient, store Store, queueSize int) *chartWorker {
+	w := &chartWorker{client: client, store: store, queue: make(chan chartJob, queueSize)}
+	go w.run()
+	return w
+}
+
+func (w *chartWorker) run() {
+	for job := range w.queue {
+		if err := w.render(job); err != nil {
+			fmt.Printf("[Chart] render failed: %v\n", err)
+		}
+	}
+}
+
+// Enqueue queues a chart job, dropping it if the queue is full rather than blocking the caller.
+func (w *chartWorker) Enqueue(job chartJob) bool {
+	select {
+	case w.queue <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *chartWorker) render(job chartJob) error {
+	var png []byte
+	var err error
+	switch job.kind {
+	case chartKindInbound:
+		png, err = w.renderInbound(job)
+	case chartKindUsers:
+		png, err = w.renderUsers(job)
+	default:
+		png, err = w.renderTraffic(job)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to render chart: %v", err)
+	}
+
+	return w.client.SendPhoto(job.ctx, job.chatID, bytes.NewReader(png), job.caption)
+}
+
+func (w *chartWorker) renderTraffic(job chartJob) ([]byte, error) {
+	buckets, err := w.store.Aggregate(job.ctx, job.bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate for chart: %v", err)
+	}
+	return renderTrafficChart(windowBuckets(buckets, job.window))
+}
+
+func (w *chartWorker) renderInbound(job chartJob) ([]byte, error) {
+	byInbound, err := w.store.AggregateByInbound(job.ctx, job.bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate by inbound for chart: %v", err)
+	}
+	windowed := make(map[string][]Bucketed, len(byInbound))
+	for tag, buckets := range byInbound {
+		windowed[tag] = windowBuckets(buckets, job.window)
+	}
+	return renderInboundStackedChart(windowed)
+}
+
+func (w *chartWorker) renderUsers(job chartJob) ([]byte, error) {
+	stats, err := defaultXRAYClient.QueryStats(job.ctx, "", false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query live stats for chart: %v", err)
+	}
+	return renderUserTopNChart(stats.Users, chartUserTopN)
+}
+
+// windowBuckets drops every bucket older than window relative to now.
+func windowBuckets(buckets []Bucketed, window time.Duration) []Bucketed {
+	cutoff := time.Now().Add(-window)
+	var windowed []Bucketed
+	for _, b := range buckets {
+		if b.BucketStart.After(cutoff) {
+			windowed = append(windowed, b)
+		}
+	}
+	return windowed
+}
+
+// renderTrafficChart draws uplink/downlink as two lines over time and returns a PNG.
+func renderTrafficChart(buckets []Bucketed) ([]byte, error) {
+	p := plot.New()
+	p.Title.Text = "Traffic over time"
+	p.X.Label.Text = "Time"
+	p.Y.Label.Text = "Bytes"
+
+	uplink := make(plotter.XYs, len(buckets))
+	downlink := make(plotter.XYs, len(buckets))
+	for i, b := range buckets {
+		x := float64(b.BucketStart.Unix())
+		uplink[i].X = x
+		uplink[i].Y = float64(b.Uplink)
+		downlink[i].X = x
+		downlink[i].Y = float64(b.Downlink)
+	}
+
+	upLine, err := plotter.NewLine(uplink)
+	if err != nil {
+		return nil, err
+	}
+	upLine.Color = color.RGBA{R: 0, G: 153, B: 76, A: 255}
+
+	downLine, err := plotter.NewLine(downlink)
+	if err != nil {
+		return nil, err
+	}
+	downLine.Color = color.RGBA{R: 204, G: 51, B: 51, A: 255}
+
+	p.Add(upLine, downLine)
+	p.Legend.Add("uplink", upLine)
+	p.Legend.Add("downlink", downLine)
+
+	return writeChartPNG(p)
+}
+
+// chartPalette is a small set of distinct colors cycled across series in a multi-series
+// chart; chosen to stay readable against white and against each other.
+var chartPalette = []color.Color{
+	color.RGBA{R: 0, G: 153, B: 76, A: 255},
+	color.RGBA{R: 204, G: 51, B: 51, A: 255},
+	color.RGBA{R: 51, G: 102, B: 204, A: 255},
+	color.RGBA{R: 230, G: 159, B: 0, A: 255},
+	color.RGBA{R: 142, G: 68, B: 173, A: 255},
+	color.RGBA{R: 26, G: 188, B: 156, A: 255},
+}
+
+// renderInboundStackedChart draws per-inbound total traffic (uplink+downlink) as a
+// stacked area over time and returns a PNG. Each inbound's band is the polygon between its
+// cumulative total before and after it's added, so the top of the stack is the combined
+// traffic across every inbound.
+func renderInboundStackedChart(byInbound map[string][]Bucketed) ([]byte, error) {
+	tags := make([]string, 0, len(byInbound))
+	for tag := range byInbound {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	timeSet := make(map[int64]bool)
+	for _, buckets := range byInbound {
+		for _, b := range buckets {
+			timeSet[b.BucketStart.Unix()] = true
+		}
+	}
+	times := make([]int64, 0, len(timeSet))
+	for t := range timeSet {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	p := plot.New()
+	p.Title.Text = "Traffic by inbound"
+	p.X.Label.Text = "Time"
+	p.Y.Label.Text = "Bytes"
+
+	if len(times) == 0 {
+		return writeChartPNG(p)
+	}
+
+	totalAt := make([]map[int64]int64, len(tags))
+	for i, tag := range tags {
+		m := make(map[int64]int64, len(times))
+		for _, b := range byInbound[tag] {
+			m[b.BucketStart.Unix()] = b.Uplink + b.Downlink
+		}
+		totalAt[i] = m
+	}
+
+	cumulative := make([]float64, len(times))
+	for i, tag := range tags {
+		lower := make(plotter.XYs, len(times))
+		upper := make(plotter.XYs, len(times))
+		for j, t := range times {
+			lower[j] = plotter.XY{X: float64(t), Y: cumulative[j]}
+			cumulative[j] += float64(totalAt[i][t])
+			upper[j] = plotter.XY{X: float64(t), Y: cumulative[j]}
+		}
+
+		band := make(plotter.XYs, 0, len(lower)+len(upper))
+		band = append(band, lower...)
+		for j := len(upper) - 1; j >= 0; j-- {
+			band = append(band, upper[j])
+		}
+
+		area, err := plotter.NewPolygon(band)
+		if err != nil {
+			return nil, err
+		}
+		area.Color = chartPalette[i%len(chartPalette)]
+		area.LineStyle.Width = 0
+		p.Add(area)
+		p.Legend.Add(tag, area)
+	}
+
+	return writeChartPNG(p)
+}
+
+// renderUserTopNChart draws the top N users by total traffic (uplink+downlink) as a bar
+// chart and returns a PNG. Only a live snapshot is available - per-user traffic isn't
+// persisted to the traffic store, so this can't be windowed like the other charts.
+func renderUserTopNChart(users map[string]TrafficPair, topN int) ([]byte, error) {
+	type userTotal struct {
+		email string
+		total int64
+	}
+	totals := make([]userTotal, 0, len(users))
+	for email, pair := range users {
+		totals = append(totals, userTotal{email: email, total: pair.Uplink + pair.Downlink})
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].total > totals[j].total })
+	if len(totals) > topN {
+		totals = totals[:topN]
+	}
+
+	p := plot.New()
+	p.Title.Text = fmt.Sprintf("Top %d users by traffic", topN)
+	p.Y.Label.Text = "Bytes"
+
+	values := make(plotter.Values, len(totals))
+	labels := make([]string, len(totals))
+	for i, ut := range totals {
+		values[i] = float64(ut.total)
+		labels[i] = ut.email
+	}
+
+	bars, err := plotter.NewBarChart(values, vg.Points(20))
+	if err != nil {
+		return nil, err
+	}
+	bars.Color = chartPalette[2]
+	p.Add(bars)
+	p.NominalX(labels...)
+
+	return writeChartPNG(p)
+}
+
+// writeChartPNG renders p to a PNG byte slice at the chart subsystem's standard size.
+func writeChartPNG(p *plot.Plot) ([]byte, error) {
+	writer, err := p.WriterTo(8*vg.Inch, 4*vg.Inch, "png")
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if _, err := writer.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// chartPeriodWindow maps a /chart period argument to its lookback window and bucket size.
+func chartPeriodWindow(period string) (window, bucket time.Duration, ok bool) {
+	switch period {
+	case "1h":
+		return time.Hour, time.Minute, true
+	case "24h":
+		return 24 * time.Hour, time.Hour, true
+	case "7d":
+		return 7 * 24 * time.Hour, time.Hour, true
+	default:
+		return 0, 0, false
+	}
+}
+
+// defaultChartWorker is the process-wide chart renderer, set by StartMonitoring once a
+// traffic store and Telegram client are available. It stays nil (charts disabled) otherwise.
+var defaultChartWorker *chartWorker
+
+// StartDailyChartReport schedules a daily summary chart to chatID at DAILY_REPORT_HOUR
+// (0-23, default 9, local time).
+func StartDailyChartReport(ctx context.Context, chatID string) {
+	hour := 9
+	if raw := os.Getenv("DAILY_REPORT_HOUR"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed >= 0 && parsed <= 23 {
+			hour = parsed
+		}
+	}
+
+	go func() {
+		for {
+			wait := time.Until(nextOccurrenceOfHour(hour))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+
+			if defaultChartWorker == nil {
+				continue
+			}
+			sent := defaultChartWorker.Enqueue(chartJob{
+				ctx:     ctx,
+				chatID:  chatID,
+				window:  24 * time.Hour,
+				bucket:  time.Hour,
+				caption: "Daily traffic summary",
+			})
+			if !sent {
+				fmt.Println("[Chart] daily report dropped, queue full")
+			}
+		}
+	}()
+}
+
+// nextOccurrenceOfHour returns the next time.Time at the given local hour, today if it
+// hasn't passed yet, otherwise tomorrow.
+func nextOccurrenceOfHour(hour int) time.Time {
+	now := time.Now()
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.Add(24 * time.Hour)
+	}
+	return next
+}
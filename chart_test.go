@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChartPeriodWindow(t *testing.T) {
+	tests := []struct {
+		period     string
+		wantWindow time.Duration
+		wantBucket time.Duration
+		wantOK     bool
+	}{
+		{"1h", time.Hour, time.Minute, true},
+		{"24h", 24 * time.Hour, time.Hour, true},
+		{"7d", 7 * 24 * time.Hour, time.Hour, true},
+		{"30d", 0, 0, false},
+		{"", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			window, bucket, ok := chartPeriodWindow(tt.period)
+			if window != tt.wantWindow || bucket != tt.wantBucket || ok != tt.wantOK {
+				t.Errorf("chartPeriodWindow(%q) = (%v, %v, %v), want (%v, %v, %v)",
+					tt.period, window, bucket, ok, tt.wantWindow, tt.wantBucket, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRenderInboundStackedChartProducesPNG(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	byInbound := map[string][]Bucketed{
+		"proxy": {{BucketStart: base, Uplink: 10, Downlink: 20}},
+		"vless": {{BucketStart: base, Uplink: 5, Downlink: 5}},
+	}
+
+	png, err := renderInboundStackedChart(byInbound)
+	if err != nil {
+		t.Fatalf("renderInboundStackedChart: %v", err)
+	}
+	if len(png) == 0 {
+		t.Error("renderInboundStackedChart returned no bytes")
+	}
+}
+
+func TestRenderUserTopNChartProducesPNGWithMoreUsersThanN(t *testing.T) {
+	users := map[string]TrafficPair{
+		"a@example.com": {Uplink: 300},
+		"b@example.com": {Uplink: 200},
+		"c@example.com": {Uplink: 100},
+	}
+
+	png, err := renderUserTopNChart(users, 2)
+	if err != nil {
+		t.Fatalf("renderUserTopNChart: %v", err)
+	}
+	if len(png) == 0 {
+		t.Error("renderUserTopNChart returned no bytes")
+	}
+}
+
+func TestWindowBuckets(t *testing.T) {
+	now := time.Now()
+	buckets := []Bucketed{
+		{BucketStart: now.Add(-2 * time.Hour)},
+		{BucketStart: now.Add(-30 * time.Minute)},
+		{BucketStart: now.Add(-1 * time.Minute)},
+	}
+
+	got := windowBuckets(buckets, time.Hour)
+	if len(got) != 2 {
+		t.Fatalf("windowBuckets returned %d buckets, want 2: %+v", len(got), got)
+	}
+	if !got[0].BucketStart.Equal(buckets[1].BucketStart) || !got[1].BucketStart.Equal(buckets[2].BucketStart) {
+		t.Errorf("windowBuckets dropped the wrong entries: %+v", got)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	xrayActiveConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "xray_active_connections",
+		Help: "Inbounds with nonzero traffic in the most recent tick",
+	})
+	xrayTrafficBytesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "xray_traffic_bytes_total",
+		Help: "Cumulative XRAY traffic in bytes, by direction and inbound",
+	}, []string{"direction", "inbound"})
+	xrayUserTrafficBytesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "xray_user_traffic_bytes_total",
+		Help: "Cumulative XRAY traffic in bytes, by direction and user email",
+	}, []string{"direction", "email"})
+	xraySysUptimeSeconds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "xray_sys_uptime_seconds",
+		Help: "XRAY process uptime in seconds",
+	})
+	xraySysGoroutines = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "xray_sys_goroutines",
+		Help: "Goroutine count reported by XRAY's runtime stats",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		xrayActiveConnections,
+		xrayTrafficBytesTotal,
+		xrayUserTrafficBytesTotal,
+		xraySysUptimeSeconds,
+		xraySysGoroutines,
+	)
+}
+
+// metricsMaxUserLabels caps how many distinct user emails get their own label series,
+// read from METRICS_MAX_USER_LABELS (default 200), to bound label cardinality.
+func metricsMaxUserLabels() int {
+	limit := 200
+	if raw := os.Getenv("METRICS_MAX_USER_LABELS"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	return limit
+}
+
+// updateTrafficMetrics folds a QueryStats snapshot into the traffic gauges/counters.
+func updateTrafficMetrics(stats *Stats, activeConns int64) {
+	xrayActiveConnections.Set(float64(activeConns))
+
+	for tag, pair := range stats.Inbounds {
+		xrayTrafficBytesTotal.WithLabelValues("uplink", tag).Add(float64(pair.Uplink))
+		xrayTrafficBytesTotal.WithLabelValues("downlink", tag).Add(float64(pair.Downlink))
+	}
+
+	recordUserTrafficMetrics(stats.Users, metricsMaxUserLabels())
+}
+
+// recordUserTrafficMetrics adds each user's traffic to xrayUserTrafficBytesTotal, capped at
+// maxUsers distinct label series. The stats snapshot is already a destructive read (XRAY
+// zeroes the counters it reports), so any user left out here loses that interval's traffic
+// for good - it isn't picked up next tick. To keep the total conserved and the drop
+// deterministic, users are ranked by traffic first and the excluded tail is folded into a
+// single "_other_" series instead of depending on Go's randomized map iteration order.
+func recordUserTrafficMetrics(users map[string]TrafficPair, maxUsers int) {
+	type userTraffic struct {
+		email string
+		pair  TrafficPair
+	}
+	ranked := make([]userTraffic, 0, len(users))
+	for email, pair := range users {
+		ranked = append(ranked, userTraffic{email: email, pair: pair})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		ti := ranked[i].pair.Uplink + ranked[i].pair.Downlink
+		tj := ranked[j].pair.Uplink + ranked[j].pair.Downlink
+		if ti != tj {
+			return ti > tj
+		}
+		return ranked[i].email < ranked[j].email
+	})
+
+	var other TrafficPair
+	for i, ut := range ranked {
+		if i < maxUsers {
+			xrayUserTrafficBytesTotal.WithLabelValues("uplink", ut.email).Add(float64(ut.pair.Uplink))
+			xrayUserTrafficBytesTotal.WithLabelValues("downlink", ut.email).Add(float64(ut.pair.Downlink))
+			continue
+		}
+		other.Uplink += ut.pair.Uplink
+		other.Downlink += ut.pair.Downlink
+	}
+	if other.Uplink > 0 || other.Downlink > 0 {
+		xrayUserTrafficBytesTotal.WithLabelValues("uplink", "_other_").Add(float64(other.Uplink))
+		xrayUserTrafficBytesTotal.WithLabelValues("downlink", "_other_").Add(float64(other.Downlink))
+	}
+}
+
+// updateSysMetrics folds a GetSysStats snapshot into the runtime gauges.
+func updateSysMetrics(sys SysStats) {
+	xraySysUptimeSeconds.Set(float64(sys.Uptime))
+	xraySysGoroutines.Set(float64(sys.NumGoroutine))
+}
+
+// StartMetricsServer exposes the Prometheus exporter on its own listener so a broken
+// stats-API or bot listener can't take /metrics down with it. Configurable via
+// METRICS_ADDR (default ":9090") and METRICS_PATH (default "/metrics").
+func StartMetricsServer() {
+	addr := os.Getenv("METRICS_ADDR")
+	if addr == "" {
+		addr = ":9090"
+	}
+	path := os.Getenv("METRICS_PATH")
+	if path == "" {
+		path = "/metrics"
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, promhttp.Handler())
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			fmt.Printf("[Metrics] server stopped: %v\n", err)
+		}
+	}()
+	fmt.Println("[Metrics]", path, "listening on", addr)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMetricsMaxUserLabels(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		want int
+	}{
+		{"unset defaults to 200", "", 200},
+		{"valid override", "50", 50},
+		{"invalid falls back to default", "not-a-number", 200},
+		{"non-positive falls back to default", "0", 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env == "" {
+				os.Unsetenv("METRICS_MAX_USER_LABELS")
+			} else {
+				os.Setenv("METRICS_MAX_USER_LABELS", tt.env)
+				defer os.Unsetenv("METRICS_MAX_USER_LABELS")
+			}
+			if got := metricsMaxUserLabels(); got != tt.want {
+				t.Errorf("metricsMaxUserLabels() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// userTrafficTotal reads back the counter value recorded for email/direction, or 0 if
+// that label combination was never observed.
+func userTrafficTotal(t *testing.T, direction, email string) float64 {
+	t.Helper()
+	metric := &dto.Metric{}
+	counter, err := xrayUserTrafficBytesTotal.GetMetricWithLabelValues(direction, email)
+	if err != nil {
+		t.Fatalf("GetMetricWithLabelValues: %v", err)
+	}
+	if err := counter.Write(metric); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	return metric.GetCounter().GetValue()
+}
+
+func TestRecordUserTrafficMetricsCapsDeterministicallyAndConservesTotal(t *testing.T) {
+	xrayUserTrafficBytesTotal.Reset()
+
+	users := map[string]TrafficPair{
+		"big@example.com":    {Uplink: 1000, Downlink: 1000},
+		"medium@example.com": {Uplink: 100, Downlink: 100},
+		"small@example.com":  {Uplink: 1, Downlink: 1},
+	}
+
+	recordUserTrafficMetrics(users, 2)
+
+	if got := userTrafficTotal(t, "uplink", "big@example.com"); got != 1000 {
+		t.Errorf("big uplink = %v, want 1000", got)
+	}
+	if got := userTrafficTotal(t, "uplink", "medium@example.com"); got != 100 {
+		t.Errorf("medium uplink = %v, want 100", got)
+	}
+	if got := userTrafficTotal(t, "uplink", "small@example.com"); got != 0 {
+		t.Errorf("small@example.com should have been excluded, got uplink = %v", got)
+	}
+
+	if got := userTrafficTotal(t, "uplink", "_other_"); got != 1 {
+		t.Errorf("_other_ uplink = %v, want 1 (the excluded small@example.com)", got)
+	}
+	if got := userTrafficTotal(t, "downlink", "_other_"); got != 1 {
+		t.Errorf("_other_ downlink = %v, want 1", got)
+	}
+}
+
+func TestRecordUserTrafficMetricsNoOtherBucketWhenUnderCap(t *testing.T) {
+	xrayUserTrafficBytesTotal.Reset()
+
+	users := map[string]TrafficPair{
+		"only@example.com": {Uplink: 5, Downlink: 5},
+	}
+
+	recordUserTrafficMetrics(users, 10)
+
+	counter, err := xrayUserTrafficBytesTotal.GetMetricWithLabelValues("uplink", "_other_")
+	if err != nil {
+		t.Fatalf("GetMetricWithLabelValues: %v", err)
+	}
+	metric := &dto.Metric{}
+	if err := counter.Write(metric); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := metric.GetCounter().GetValue(); got != 0 {
+		t.Errorf("_other_ uplink = %v, want 0 when nobody was excluded", got)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Sample is a single point-in-time traffic reading for one inbound.
+type Sample struct {
+	Timestamp   time.Time
+	Inbound     string
+	Uplink      int64
+	Downlink    int64
+	ActiveConns int64
+}
+
+// Bucketed is an aggregated traffic window returned by Store.Aggregate.
+type Bucketed struct {
+	BucketStart time.Time
+	Uplink      int64
+	Downlink    int64
+	ActiveConns int64
+}
+
+// Store persists traffic samples and serves time-range and bucketed queries over them.
+type Store interface {
+	Insert(ctx context.Context, sample Sample) error
+	Range(ctx context.Context, from, to time.Time) ([]Sample, error)
+	Aggregate(ctx context.Context, bucket time.Duration) ([]Bucketed, error)
+	AggregateRange(ctx context.Context, from, to time.Time, bucket time.Duration) ([]Bucketed, error)
+	AggregateByInbound(ctx context.Context, bucket time.Duration) (map[string][]Bucketed, error)
+	Close() error
+}
+
+// sqliteStore is a Store backed by modernc.org/sqlite (CGO-free).
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// trafficStore is the process-wide history store, set by StartMonitoring once opened.
+// It stays nil (history disabled) if DB_PATH can't be opened.
+var trafficStore Store
+
+// openTrafficStore opens the SQLite store at DB_PATH (default "traffic.db").
+func openTrafficStore() (*sqliteStore, error) {
+	path := os.Getenv("DB_PATH")
+	if path == "" {
+		path = "traffic.db"
+	}
+	return NewSQLiteStore(path)
+}
+
+// persistSamples writes one Sample per inbound for a single monitoring tick. There's no
+// separate "_all_" row: Aggregate's SUM() already totals across every inbound's rows.
+func persistSamples(ctx context.Context, store Store, stats *Stats, activeConns int64) error {
+	now := time.Now()
+	for tag, pair := range stats.Inbounds {
+		sample := Sample{
+			Timestamp:   now,
+			Inbound:     tag,
+			Uplink:      pair.Uplink,
+			Downlink:    pair.Downlink,
+			ActiveConns: activeConns,
+		}
+		if err := store.Insert(ctx, sample); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewSQLiteStore opens (and migrates) the traffic_samples table at path.
+func NewSQLiteStore(path string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite store at %s: %v", path, err)
+	}
+
+	const schema = `
+CREATE TABLE IF NOT EXISTS traffic_samples (
+	ts           INTEGER NOT NULL,
+	inbound      TEXT NOT NULL,
+	uplink       INTEGER NOT NULL,
+	downlink     INTEGER NOT NULL,
+	active_conns INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_traffic_samples_ts ON traffic_samples (ts);
+`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate sqlite store: %v", err)
+	}
+
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) Insert(ctx context.Context, sample Sample) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO traffic_samples (ts, inbound, uplink, downlink, active_conns) VALUES (?, ?, ?, ?, ?)`,
+		sample.Timestamp.Unix(), sample.Inbound, sample.Uplink, sample.Downlink, sample.ActiveConns)
+	if err != nil {
+		return fmt.Errorf("failed to insert sample: %v", err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) Range(ctx context.Context, from, to time.Time) ([]Sample, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT ts, inbound, uplink, downlink, active_conns FROM traffic_samples WHERE ts >= ? AND ts <= ? ORDER BY ts`,
+		from.Unix(), to.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query range: %v", err)
+	}
+	defer rows.Close()
+
+	var samples []Sample
+	for rows.Next() {
+		var ts int64
+		var sample Sample
+		if err := rows.Scan(&ts, &sample.Inbound, &sample.Uplink, &sample.Downlink, &sample.ActiveConns); err != nil {
+			return nil, fmt.Errorf("failed to scan sample: %v", err)
+		}
+		sample.Timestamp = time.Unix(ts, 0)
+		samples = append(samples, sample)
+	}
+	return samples, rows.Err()
+}
+
+// Aggregate buckets every stored sample into fixed-size windows of the given duration,
+// summing traffic and taking the max observed active connection count per bucket.
+func (s *sqliteStore) Aggregate(ctx context.Context, bucket time.Duration) ([]Bucketed, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT (ts / ?) * ? AS bucket_start, SUM(uplink), SUM(downlink), MAX(active_conns)
+		 FROM traffic_samples GROUP BY bucket_start ORDER BY bucket_start`,
+		int64(bucket.Seconds()), int64(bucket.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate: %v", err)
+	}
+	defer rows.Close()
+
+	var buckets []Bucketed
+	for rows.Next() {
+		var bucketStart int64
+		var b Bucketed
+		if err := rows.Scan(&bucketStart, &b.Uplink, &b.Downlink, &b.ActiveConns); err != nil {
+			return nil, fmt.Errorf("failed to scan bucket: %v", err)
+		}
+		b.BucketStart = time.Unix(bucketStart, 0)
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// AggregateRange is Aggregate, but restricted to samples in [from, to] at the database
+// level rather than bucketing the whole table and filtering the result in Go - callers
+// like the /api/stats endpoint that only want a recent window shouldn't pay to scan and
+// bucket the entire history on every request.
+func (s *sqliteStore) AggregateRange(ctx context.Context, from, to time.Time, bucket time.Duration) ([]Bucketed, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT (ts / ?) * ? AS bucket_start, SUM(uplink), SUM(downlink), MAX(active_conns)
+		 FROM traffic_samples WHERE ts >= ? AND ts <= ? GROUP BY bucket_start ORDER BY bucket_start`,
+		int64(bucket.Seconds()), int64(bucket.Seconds()), from.Unix(), to.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate range: %v", err)
+	}
+	defer rows.Close()
+
+	var buckets []Bucketed
+	for rows.Next() {
+		var bucketStart int64
+		var b Bucketed
+		if err := rows.Scan(&bucketStart, &b.Uplink, &b.Downlink, &b.ActiveConns); err != nil {
+			return nil, fmt.Errorf("failed to scan bucket: %v", err)
+		}
+		b.BucketStart = time.Unix(bucketStart, 0)
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// AggregateByInbound is Aggregate, but grouped by inbound as well as bucket, for callers
+// that need a per-inbound breakdown (e.g. the stacked-area chart) rather than a flat total.
+func (s *sqliteStore) AggregateByInbound(ctx context.Context, bucket time.Duration) (map[string][]Bucketed, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT inbound, (ts / ?) * ? AS bucket_start, SUM(uplink), SUM(downlink), MAX(active_conns)
+		 FROM traffic_samples GROUP BY inbound, bucket_start ORDER BY inbound, bucket_start`,
+		int64(bucket.Seconds()), int64(bucket.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate by inbound: %v", err)
+	}
+	defer rows.Close()
+
+	byInbound := make(map[string][]Bucketed)
+	for rows.Next() {
+		var inbound string
+		var bucketStart int64
+		var b Bucketed
+		if err := rows.Scan(&inbound, &bucketStart, &b.Uplink, &b.Downlink, &b.ActiveConns); err != nil {
+			return nil, fmt.Errorf("failed to scan bucket: %v", err)
+		}
+		b.BucketStart = time.Unix(bucketStart, 0)
+		byInbound[inbound] = append(byInbound[inbound], b)
+	}
+	return byInbound, rows.Err()
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+// vacuum deletes samples older than olderThan.
+func (s *sqliteStore) vacuum(ctx context.Context, olderThan time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM traffic_samples WHERE ts < ?`, olderThan.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to vacuum: %v", err)
+	}
+	return res.RowsAffected()
+}
+
+// StartRetentionVacuum runs a background goroutine that deletes samples older than
+// RETENTION_DAYS (default 30) once per day.
+func StartRetentionVacuum(ctx context.Context, store *sqliteStore) {
+	retentionDays := 30
+	if raw := os.Getenv("RETENTION_DAYS"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			retentionDays = parsed
+		}
+	}
+	retention := time.Duration(retentionDays) * 24 * time.Hour
+
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cutoff := time.Now().Add(-retention)
+				deleted, err := store.vacuum(ctx, cutoff)
+				if err != nil {
+					fmt.Printf("[Store] Retention vacuum failed: %v\n", err)
+					continue
+				}
+				fmt.Printf("[Store] Retention vacuum removed %d samples older than %s\n", deleted, cutoff.Format(time.RFC3339))
+			}
+		}
+	}()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *sqliteStore {
+	t.Helper()
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestAggregateBucketsSamples(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	samples := []Sample{
+		{Timestamp: base, Inbound: "proxy", Uplink: 10, Downlink: 20, ActiveConns: 1},
+		{Timestamp: base.Add(30 * time.Second), Inbound: "proxy", Uplink: 5, Downlink: 5, ActiveConns: 3},
+		{Timestamp: base.Add(time.Minute), Inbound: "proxy", Uplink: 1, Downlink: 1, ActiveConns: 1},
+	}
+	for _, s := range samples {
+		if err := store.Insert(ctx, s); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+
+	buckets, err := store.Aggregate(ctx, time.Minute)
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+	if len(buckets) != 2 {
+		t.Fatalf("got %d buckets, want 2: %+v", len(buckets), buckets)
+	}
+
+	first := buckets[0]
+	if first.Uplink != 15 || first.Downlink != 25 || first.ActiveConns != 3 {
+		t.Errorf("first bucket = %+v, want {Uplink:15 Downlink:25 ActiveConns:3}", first)
+	}
+
+	second := buckets[1]
+	if second.Uplink != 1 || second.Downlink != 1 || second.ActiveConns != 1 {
+		t.Errorf("second bucket = %+v, want {Uplink:1 Downlink:1 ActiveConns:1}", second)
+	}
+}
+
+func TestAggregateRangeRestrictsToWindow(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	samples := []Sample{
+		{Timestamp: base, Inbound: "proxy", Uplink: 1, Downlink: 1, ActiveConns: 1},
+		{Timestamp: base.Add(time.Hour), Inbound: "proxy", Uplink: 10, Downlink: 20, ActiveConns: 1},
+		{Timestamp: base.Add(2 * time.Hour), Inbound: "proxy", Uplink: 100, Downlink: 200, ActiveConns: 1},
+	}
+	for _, s := range samples {
+		if err := store.Insert(ctx, s); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+
+	buckets, err := store.AggregateRange(ctx, base.Add(30*time.Minute), base.Add(90*time.Minute), time.Hour)
+	if err != nil {
+		t.Fatalf("AggregateRange: %v", err)
+	}
+	if len(buckets) != 1 {
+		t.Fatalf("got %d buckets, want 1 (only the middle sample is in range): %+v", len(buckets), buckets)
+	}
+	if buckets[0].Uplink != 10 || buckets[0].Downlink != 20 {
+		t.Errorf("bucket = %+v, want {Uplink:10 Downlink:20}", buckets[0])
+	}
+}
+
+func TestAggregateByInboundGroupsPerInbound(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	samples := []Sample{
+		{Timestamp: base, Inbound: "proxy", Uplink: 10, Downlink: 20, ActiveConns: 1},
+		{Timestamp: base, Inbound: "vless", Uplink: 100, Downlink: 200, ActiveConns: 1},
+		{Timestamp: base.Add(time.Minute), Inbound: "proxy", Uplink: 1, Downlink: 1, ActiveConns: 1},
+	}
+	for _, s := range samples {
+		if err := store.Insert(ctx, s); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+
+	byInbound, err := store.AggregateByInbound(ctx, time.Minute)
+	if err != nil {
+		t.Fatalf("AggregateByInbound: %v", err)
+	}
+
+	if len(byInbound["proxy"]) != 2 {
+		t.Errorf("proxy buckets = %d, want 2", len(byInbound["proxy"]))
+	}
+	if len(byInbound["vless"]) != 1 {
+		t.Errorf("vless buckets = %d, want 1", len(byInbound["vless"]))
+	}
+	if got := byInbound["vless"][0]; got.Uplink != 100 || got.Downlink != 200 {
+		t.Errorf("vless bucket = %+v, want {Uplink:100 Downlink:200}", got)
+	}
+}
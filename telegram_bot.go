@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CommandHandler handles a single slash command invocation.
+type CommandHandler func(ctx context.Context, bctx *BotContext) error
+
+// BotContext is passed to every CommandHandler with the data needed to respond.
+type BotContext struct {
+	ChatID string
+	Args   []string
+	Reply  func(text string) error
+}
+
+// telegramUpdate mirrors the subset of Telegram's Update object the bot cares about.
+type telegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *telegramMessage `json:"message"`
+}
+
+type telegramMessage struct {
+	Text string `json:"text"`
+	Chat struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+}
+
+type telegramUpdatesResponse struct {
+	OK     bool             `json:"ok"`
+	Result []telegramUpdate `json:"result"`
+}
+
+// TelegramBot long-polls getUpdates and dispatches incoming commands to registered handlers.
+type TelegramBot struct {
+	BotToken       string
+	AllowedChatIDs map[string]bool // nil/empty means every chat is allowed
+
+	mu       sync.RWMutex
+	handlers map[string]CommandHandler
+
+	offset     int64
+	httpClient *http.Client
+}
+
+// NewTelegramBot constructs a bot for the given token, optionally restricted to an allow-list
+// of chat IDs loaded from ALLOWED_CHAT_IDS (comma-separated).
+func NewTelegramBot(botToken string) *TelegramBot {
+	return &TelegramBot{
+		BotToken:       botToken,
+		AllowedChatIDs: parseAllowedChatIDs(os.Getenv("ALLOWED_CHAT_IDS")),
+		handlers:       make(map[string]CommandHandler),
+		httpClient:     &http.Client{Timeout: 35 * time.Second},
+	}
+}
+
+func parseAllowedChatIDs(raw string) map[string]bool {
+	if raw == "" {
+		return nil
+	}
+	allowed := make(map[string]bool)
+	for _, id := range strings.Split(raw, ",") {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			allowed[id] = true
+		}
+	}
+	return allowed
+}
+
+// RegisterCommand adds or replaces the handler for a command name (without the leading "/").
+func (b *TelegramBot) RegisterCommand(name string, handler CommandHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[name] = handler
+}
+
+func (b *TelegramBot) isAllowed(chatID string) bool {
+	if len(b.AllowedChatIDs) == 0 {
+		return true
+	}
+	return b.AllowedChatIDs[chatID]
+}
+
+// Run starts the getUpdates long-poll loop. It blocks until ctx is cancelled.
+func (b *TelegramBot) Run(ctx context.Context) {
+	fmt.Println("[TelegramBot] Starting command loop")
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("[TelegramBot] Stopping command loop")
+			return
+		default:
+		}
+
+		updates, err := b.getUpdates(ctx)
+		if err != nil {
+			fmt.Printf("[TelegramBot] getUpdates error: %v\n", err)
+			time.Sleep(3 * time.Second)
+			continue
+		}
+
+		for _, update := range updates {
+			if update.UpdateID >= b.offset {
+				b.offset = update.UpdateID + 1
+			}
+			b.dispatch(ctx, update)
+		}
+	}
+}
+
+// getUpdates performs a single long-poll call against Telegram's getUpdates endpoint.
+func (b *TelegramBot) getUpdates(ctx context.Context) ([]telegramUpdate, error) {
+	endpoint := fmt.Sprintf("https://api.telegram.org/bot%s/getUpdates?timeout=30&offset=%d", b.BotToken, b.offset)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("getUpdates returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	var parsed telegramUpdatesResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse getUpdates response: %v", err)
+	}
+	if !parsed.OK {
+		return nil, fmt.Errorf("getUpdates response not ok: %s", string(body))
+	}
+	return parsed.Result, nil
+}
+
+// dispatch parses a single update's message text and routes it to the matching command handler.
+func (b *TelegramBot) dispatch(ctx context.Context, update telegramUpdate) {
+	if update.Message == nil || !strings.HasPrefix(update.Message.Text, "/") {
+		return
+	}
+
+	chatID := strconv.FormatInt(update.Message.Chat.ID, 10)
+	if !b.isAllowed(chatID) {
+		fmt.Printf("[TelegramBot] Ignoring command from disallowed chat %s\n", chatID)
+		return
+	}
+
+	fields := strings.Fields(update.Message.Text)
+	name := strings.TrimPrefix(fields[0], "/")
+	if idx := strings.Index(name, "@"); idx != -1 {
+		name = name[:idx] // strip "@botname" suffix group chats append
+	}
+
+	b.mu.RLock()
+	handler, ok := b.handlers[name]
+	b.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	bctx := &BotContext{
+		ChatID: chatID,
+		Args:   fields[1:],
+		Reply: func(text string) error {
+			return sendTelegramMessage(b.BotToken, chatID, text)
+		},
+	}
+
+	if err := handler(ctx, bctx); err != nil {
+		fmt.Printf("[TelegramBot] Handler for /%s failed: %v\n", name, err)
+	}
+}
+
+// botState holds the small amount of mutable state the built-in command handlers share.
+type botState struct {
+	mu            sync.Mutex
+	subscriptions map[string]chan struct{} // chatID -> stop channel for its push goroutine
+	resetAt       time.Time
+}
+
+func newBotState() *botState {
+	return &botState{
+		subscriptions: make(map[string]chan struct{}),
+		resetAt:       time.Now(),
+	}
+}
+
+// subscribe (re)starts a goroutine that pushes a stats snapshot to chatID on bot every
+// interval, replacing any subscription the chat already had.
+func (s *botState) subscribe(bot *TelegramBot, chatID string, interval time.Duration) {
+	s.mu.Lock()
+	if stop, ok := s.subscriptions[chatID]; ok {
+		close(stop)
+	}
+	stop := make(chan struct{})
+	s.subscriptions[chatID] = stop
+	s.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				info, err := getXRAYStatsSnapshot(context.Background())
+				if err != nil {
+					fmt.Printf("[TelegramBot] subscription push to %s failed: %v\n", chatID, err)
+					continue
+				}
+				text := fmt.Sprintf(
+					"<b>📊 Subscribed Update</b>\n<b>Active Inbounds:</b> %d\n<b>Upload:</b> %s\n<b>Download:</b> %s\n<b>Total:</b> %s",
+					info.ActiveConnections, formatTraffic(info.UploadTraffic), formatTraffic(info.DownloadTraffic), formatTraffic(info.TotalTraffic))
+				if err := sendTelegramMessage(bot.BotToken, chatID, text); err != nil {
+					fmt.Printf("[TelegramBot] subscription push to %s failed: %v\n", chatID, err)
+				}
+			}
+		}
+	}()
+}
+
+// reset zeroes XRAY's traffic counters immediately and records when that happened.
+func (s *botState) reset(ctx context.Context) error {
+	if _, err := defaultXRAYClient.QueryStats(ctx, "", true); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.resetAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// trafficPeriodWindow maps a /traffic period argument to its lookback window and the
+// bucket resolution used when aggregating history.
+func trafficPeriodWindow(period string) (window, bucket time.Duration, ok bool) {
+	switch period {
+	case "daily":
+		return 24 * time.Hour, time.Hour, true
+	case "weekly":
+		return 7 * 24 * time.Hour, 24 * time.Hour, true
+	case "monthly":
+		return 30 * 24 * time.Hour, 24 * time.Hour, true
+	default:
+		return 0, 0, false
+	}
+}
+
+// registerBuiltinCommands wires up /stats, /traffic, /subscribe, /reset, and /help.
+func registerBuiltinCommands(bot *TelegramBot, state *botState) {
+	bot.RegisterCommand("stats", func(ctx context.Context, bctx *BotContext) error {
+		info, err := getXRAYStatsSnapshot(ctx)
+		if err != nil {
+			return bctx.Reply(fmt.Sprintf("Failed to fetch stats: %v", err))
+		}
+		text := fmt.Sprintf(
+			"<b>📊 Current Stats</b>\n<b>Active Inbounds:</b> %d\n<b>Upload:</b> %s\n<b>Download:</b> %s\n<b>Total:</b> %s\n<b>Since reset:</b> %s",
+			info.ActiveConnections,
+			formatTraffic(info.UploadTraffic),
+			formatTraffic(info.DownloadTraffic),
+			formatTraffic(info.TotalTraffic),
+			time.Since(state.resetAt).Round(time.Second),
+		)
+		return bctx.Reply(text)
+	})
+
+	bot.RegisterCommand("traffic", func(ctx context.Context, bctx *BotContext) error {
+		period := "daily"
+		if len(bctx.Args) > 0 {
+			period = strings.ToLower(bctx.Args[0])
+		}
+
+		window, bucket, ok := trafficPeriodWindow(period)
+		if !ok {
+			return bctx.Reply("Usage: /traffic [daily|weekly|monthly]")
+		}
+
+		if trafficStore == nil {
+			info, err := getXRAYStatsSnapshot(ctx)
+			if err != nil {
+				return bctx.Reply(fmt.Sprintf("Failed to fetch traffic: %v", err))
+			}
+			return bctx.Reply(fmt.Sprintf("<b>Traffic (%s)</b>\nTotal so far: %s (history disabled)", period, formatTraffic(info.TotalTraffic)))
+		}
+
+		buckets, err := trafficStore.Aggregate(ctx, bucket)
+		if err != nil {
+			return bctx.Reply(fmt.Sprintf("Failed to aggregate traffic: %v", err))
+		}
+
+		var uplink, downlink int64
+		cutoff := time.Now().Add(-window)
+		for _, b := range buckets {
+			if b.BucketStart.After(cutoff) {
+				uplink += b.Uplink
+				downlink += b.Downlink
+			}
+		}
+		return bctx.Reply(fmt.Sprintf(
+			"<b>Traffic (%s)</b>\n<b>Upload:</b> %s\n<b>Download:</b> %s\n<b>Total:</b> %s",
+			period, formatTraffic(uplink), formatTraffic(downlink), formatTraffic(uplink+downlink)))
+	})
+
+	bot.RegisterCommand("subscribe", func(ctx context.Context, bctx *BotContext) error {
+		if len(bctx.Args) == 0 {
+			return bctx.Reply("Usage: /subscribe <interval, e.g. 10m>")
+		}
+		interval, err := time.ParseDuration(bctx.Args[0])
+		if err != nil {
+			return bctx.Reply(fmt.Sprintf("Invalid interval %q: %v", bctx.Args[0], err))
+		}
+		state.subscribe(bot, bctx.ChatID, interval)
+		return bctx.Reply(fmt.Sprintf("Subscribed to updates every %s", interval))
+	})
+
+	bot.RegisterCommand("chart", func(ctx context.Context, bctx *BotContext) error {
+		period := "24h"
+		if len(bctx.Args) > 0 {
+			period = strings.ToLower(bctx.Args[0])
+		}
+		window, bucket, ok := chartPeriodWindow(period)
+		if !ok {
+			return bctx.Reply("Usage: /chart [1h|24h|7d] [traffic|inbound|users]")
+		}
+
+		kind := chartKindTraffic
+		caption := fmt.Sprintf("Traffic (%s)", period)
+		if len(bctx.Args) > 1 {
+			switch strings.ToLower(bctx.Args[1]) {
+			case "traffic":
+				// kind and caption already set above.
+			case "inbound":
+				kind = chartKindInbound
+				caption = fmt.Sprintf("Traffic by inbound (%s)", period)
+			case "users":
+				kind = chartKindUsers
+				caption = "Top users by traffic"
+			default:
+				return bctx.Reply("Usage: /chart [1h|24h|7d] [traffic|inbound|users]")
+			}
+		}
+		if defaultChartWorker == nil {
+			return bctx.Reply("Charts are disabled (no traffic history store configured)")
+		}
+
+		sent := defaultChartWorker.Enqueue(chartJob{
+			ctx:     context.Background(),
+			chatID:  bctx.ChatID,
+			kind:    kind,
+			window:  window,
+			bucket:  bucket,
+			caption: caption,
+		})
+		if !sent {
+			return bctx.Reply("Chart queue is full, try again shortly")
+		}
+		return bctx.Reply("Generating chart...")
+	})
+
+	bot.RegisterCommand("reset", func(ctx context.Context, bctx *BotContext) error {
+		if err := state.reset(ctx); err != nil {
+			return bctx.Reply(fmt.Sprintf("Failed to reset counters: %v", err))
+		}
+		return bctx.Reply("Counters reset")
+	})
+
+	bot.RegisterCommand("help", func(ctx context.Context, bctx *BotContext) error {
+		return bctx.Reply(`<b>Available commands</b>
+/stats - current connection snapshot
+/traffic [daily|weekly|monthly] - traffic summary
+/chart [1h|24h|7d] [traffic|inbound|users] - traffic chart, per-inbound breakdown, or top users
+/subscribe &lt;interval&gt; - change your push frequency
+/reset - reset counters
+/help - show this message`)
+	})
+}
+
+// StartTelegramBot starts the command-handling bot if BOT_TOKEN and CHAT_ID are configured. It runs
+// independently of StartMonitoring's periodic alert push.
+func StartTelegramBot(ctx context.Context) {
+	botToken, _, ok := getTelegramEnv()
+	if !ok {
+		return
+	}
+
+	bot := NewTelegramBot(botToken)
+	state := newBotState()
+	registerBuiltinCommands(bot, state)
+	go bot.Run(ctx)
+	fmt.Println("[TelegramBot] Command handling enabled")
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAllowedChatIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want map[string]bool
+	}{
+		{"empty", "", nil},
+		{"single", "123", map[string]bool{"123": true}},
+		{"multiple with spaces", "123, 456 ,789", map[string]bool{"123": true, "456": true, "789": true}},
+		{"blank entries skipped", "123,,456", map[string]bool{"123": true, "456": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseAllowedChatIDs(tt.raw)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAllowedChatIDs(%q) = %#v, want %#v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrafficPeriodWindow(t *testing.T) {
+	tests := []struct {
+		period     string
+		wantWindow time.Duration
+		wantBucket time.Duration
+		wantOK     bool
+	}{
+		{"daily", 24 * time.Hour, time.Hour, true},
+		{"weekly", 7 * 24 * time.Hour, 24 * time.Hour, true},
+		{"monthly", 30 * 24 * time.Hour, 24 * time.Hour, true},
+		{"yearly", 0, 0, false},
+		{"", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			window, bucket, ok := trafficPeriodWindow(tt.period)
+			if window != tt.wantWindow || bucket != tt.wantBucket || ok != tt.wantOK {
+				t.Errorf("trafficPeriodWindow(%q) = (%v, %v, %v), want (%v, %v, %v)",
+					tt.period, window, bucket, ok, tt.wantWindow, tt.wantBucket, tt.wantOK)
+			}
+		})
+	}
+}
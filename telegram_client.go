@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// sendMessageRequest is the typed JSON body for Telegram's sendMessage endpoint.
+type sendMessageRequest struct {
+	ChatID    string `json:"chat_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode,omitempty"`
+}
+
+// telegramErrorResponse is Telegram's error envelope, returned on non-2xx responses.
+type telegramErrorResponse struct {
+	OK          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// TelegramClient is a rate-limited, retrying HTTP client for the Telegram Bot API.
+// It honors Telegram's global 30 msg/sec limit and its per-chat 1 msg/sec limit, and
+// backs off on 429 responses using the server-provided retry_after when present.
+type TelegramClient struct {
+	BotToken   string
+	httpClient *http.Client
+	global     *rate.Limiter
+
+	mu      sync.Mutex
+	perChat map[string]*rate.Limiter
+
+	// apiBaseURL, backoffBase, and maxAttempts are overridable by tests; production
+	// callers always get the defaults set by NewTelegramClient.
+	apiBaseURL  string
+	backoffBase time.Duration
+	maxAttempts int
+}
+
+// NewTelegramClient builds a client for botToken with the repo's default rate limits:
+// 25 req/s globally and 1 req/s per chat, leaving headroom under Telegram's own caps.
+func NewTelegramClient(botToken string) *TelegramClient {
+	return &TelegramClient{
+		BotToken:    botToken,
+		httpClient:  &http.Client{Timeout: 15 * time.Second},
+		global:      rate.NewLimiter(25, 25),
+		perChat:     make(map[string]*rate.Limiter),
+		apiBaseURL:  "https://api.telegram.org",
+		backoffBase: time.Second,
+		maxAttempts: 5,
+	}
+}
+
+func (c *TelegramClient) chatLimiter(chatID string) *rate.Limiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	limiter, ok := c.perChat[chatID]
+	if !ok {
+		limiter = rate.NewLimiter(1, 1)
+		c.perChat[chatID] = limiter
+	}
+	return limiter
+}
+
+// SendMessage sends a text message with parse_mode HTML.
+func (c *TelegramClient) SendMessage(ctx context.Context, chatID, text string) error {
+	return c.execute(ctx, chatID, "sendMessage", func() (*http.Request, error) {
+		body, err := json.Marshal(sendMessageRequest{ChatID: chatID, Text: text, ParseMode: "HTML"})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal sendMessage payload: %v", err)
+		}
+		req, err := http.NewRequestWithContext(ctx, "POST", c.methodURL("sendMessage"), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+}
+
+// SendPhoto uploads img as a photo via multipart/form-data.
+func (c *TelegramClient) SendPhoto(ctx context.Context, chatID string, img io.Reader, caption string) error {
+	data, err := io.ReadAll(img)
+	if err != nil {
+		return fmt.Errorf("failed to read photo: %v", err)
+	}
+	return c.execute(ctx, chatID, "sendPhoto", func() (*http.Request, error) {
+		return c.multipartRequest("sendPhoto", chatID, "photo", "chart.png", data, caption)
+	})
+}
+
+// SendDocument uploads doc as a generic file via multipart/form-data.
+func (c *TelegramClient) SendDocument(ctx context.Context, chatID, filename string, doc io.Reader, caption string) error {
+	data, err := io.ReadAll(doc)
+	if err != nil {
+		return fmt.Errorf("failed to read document: %v", err)
+	}
+	return c.execute(ctx, chatID, "sendDocument", func() (*http.Request, error) {
+		return c.multipartRequest("sendDocument", chatID, "document", filename, data, caption)
+	})
+}
+
+func (c *TelegramClient) methodURL(method string) string {
+	return fmt.Sprintf("%s/bot%s/%s", c.apiBaseURL, c.BotToken, method)
+}
+
+// multipartRequest builds a fresh multipart request for each retry attempt, since the
+// underlying file bytes have already been buffered into memory.
+func (c *TelegramClient) multipartRequest(method, chatID, fileField, filename string, data []byte, caption string) (*http.Request, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	if err := writer.WriteField("chat_id", chatID); err != nil {
+		return nil, err
+	}
+	if caption != "" {
+		if err := writer.WriteField("caption", caption); err != nil {
+			return nil, err
+		}
+	}
+	part, err := writer.CreateFormFile(fileField, filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", c.methodURL(method), &buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req, nil
+}
+
+// execute applies the global and per-chat rate limits, then sends the request built by
+// buildRequest, retrying with exponential backoff (honoring Telegram's retry_after) on 429s.
+func (c *TelegramClient) execute(ctx context.Context, chatID, method string, buildRequest func() (*http.Request, error)) error {
+	if err := c.global.Wait(ctx); err != nil {
+		return err
+	}
+	if err := c.chatLimiter(chatID).Wait(ctx); err != nil {
+		return err
+	}
+
+	backoff := c.backoffBase
+	maxAttempts := c.maxAttempts
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		req, err := buildRequest()
+		if err != nil {
+			return err
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusOK {
+			return nil
+		}
+
+		var apiErr telegramErrorResponse
+		json.Unmarshal(body, &apiErr)
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxAttempts {
+			wait := backoff
+			if apiErr.Parameters.RetryAfter > 0 {
+				wait = time.Duration(apiErr.Parameters.RetryAfter) * time.Second
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			backoff *= 2
+			continue
+		}
+
+		return fmt.Errorf("%s returned %d: %s", method, resp.StatusCode, apiErr.Description)
+	}
+
+	return fmt.Errorf("%s: exceeded %d retry attempts for chat %s", method, maxAttempts, chatID)
+}
+
+// telegramClients caches one TelegramClient per bot token so rate limiters are shared
+// across every caller (alerts, bot replies, charts).
+var (
+	telegramClientsMu sync.Mutex
+	telegramClients   = make(map[string]*TelegramClient)
+)
+
+// telegramClientFor returns the shared TelegramClient for botToken, creating it on first use.
+func telegramClientFor(botToken string) *TelegramClient {
+	telegramClientsMu.Lock()
+	defer telegramClientsMu.Unlock()
+	client, ok := telegramClients[botToken]
+	if !ok {
+		client = NewTelegramClient(botToken)
+		telegramClients[botToken] = client
+	}
+	return client
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// newTestClient builds a TelegramClient pointed at server with tight rate limits and
+// backoff so tests run fast and deterministically.
+func newTestClient(server *httptest.Server) *TelegramClient {
+	return &TelegramClient{
+		BotToken:    "test-token",
+		httpClient:  server.Client(),
+		global:      rate.NewLimiter(rate.Inf, 0),
+		perChat:     make(map[string]*rate.Limiter),
+		apiBaseURL:  server.URL,
+		backoffBase: 10 * time.Millisecond,
+		maxAttempts: 3,
+	}
+}
+
+func TestExecuteHonorsRetryAfter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"ok":false,"error_code":429,"parameters":{"retry_after":1}}`)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+
+	start := time.Now()
+	err := client.SendMessage(context.Background(), "chat1", "hi")
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+	if elapsed < time.Second {
+		t.Errorf("elapsed = %v, want >= 1s (retry_after not honored)", elapsed)
+	}
+}
+
+func TestExecuteNonRetryableErrorReturnsImmediately(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"ok":false,"error_code":400,"description":"bad request"}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+
+	start := time.Now()
+	err := client.SendMessage(context.Background(), "chat1", "hi")
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("SendMessage: want error, got nil")
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("calls = %d, want 1 (should not retry non-429 errors)", calls)
+	}
+	if elapsed > 100*time.Millisecond {
+		t.Errorf("elapsed = %v, want a near-immediate return", elapsed)
+	}
+}
+
+func TestExecuteGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"ok":false,"error_code":429,"parameters":{"retry_after":0}}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+
+	err := client.SendMessage(context.Background(), "chat1", "hi")
+
+	if err == nil {
+		t.Fatal("SendMessage: want error after exhausting retries, got nil")
+	}
+	if atomic.LoadInt32(&calls) != int32(client.maxAttempts) {
+		t.Errorf("calls = %d, want %d (maxAttempts)", calls, client.maxAttempts)
+	}
+}
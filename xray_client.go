@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	statscmd "github.com/xtls/xray-core/app/stats/command"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// TrafficPair holds an uplink/downlink byte counter pair for a single inbound, outbound, or user.
+type TrafficPair struct {
+	Uplink   int64
+	Downlink int64
+}
+
+// SysStats mirrors the subset of XRAY's runtime stats we expose.
+type SysStats struct {
+	Uptime       uint32
+	NumGoroutine uint32
+	Alloc        uint64
+	TotalAlloc   uint64
+}
+
+// Stats is the structured, parsed view of a QueryStats/GetSysStats call.
+type Stats struct {
+	Inbounds  map[string]TrafficPair
+	Outbounds map[string]TrafficPair
+	Users     map[string]TrafficPair
+	Sys       SysStats
+}
+
+// XRAYClient is a gRPC client for XRAY's app.stats.command.StatsService.
+type XRAYClient struct {
+	Addr       string
+	UseTLS     bool
+	ServerName string
+
+	mu     sync.Mutex
+	conn   *grpc.ClientConn
+	client statscmd.StatsServiceClient
+}
+
+// NewXRAYClient builds a client from XRAY_API_ADDR (default 127.0.0.1:10085),
+// XRAY_API_TLS ("1"/"true" to enable), and XRAY_API_SERVER_NAME for TLS verification.
+func NewXRAYClient() *XRAYClient {
+	addr := os.Getenv("XRAY_API_ADDR")
+	if addr == "" {
+		addr = "127.0.0.1:10085"
+	}
+	useTLS := os.Getenv("XRAY_API_TLS") == "1" || strings.EqualFold(os.Getenv("XRAY_API_TLS"), "true")
+	return &XRAYClient{
+		Addr:       addr,
+		UseTLS:     useTLS,
+		ServerName: os.Getenv("XRAY_API_SERVER_NAME"),
+	}
+}
+
+func (c *XRAYClient) dial(ctx context.Context) (statscmd.StatsServiceClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client != nil {
+		return c.client, nil
+	}
+
+	var creds grpc.DialOption
+	if c.UseTLS {
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{ServerName: c.ServerName}))
+	} else {
+		creds = grpc.WithTransportCredentials(insecure.NewCredentials())
+	}
+
+	conn, err := grpc.DialContext(ctx, c.Addr, creds, grpc.WithBlock())
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial XRAY API at %s: %v", c.Addr, err)
+	}
+
+	c.conn = conn
+	c.client = statscmd.NewStatsServiceClient(conn)
+	return c.client, nil
+}
+
+// Close releases the underlying gRPC connection, if any.
+func (c *XRAYClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	c.client = nil
+	return err
+}
+
+// QueryStats fetches every stat matching pattern and parses it into a structured Stats.
+// Passing reset=true atomically zeroes the matched counters, so the returned values are
+// the traffic accumulated since the previous reset call.
+func (c *XRAYClient) QueryStats(ctx context.Context, pattern string, reset bool) (*Stats, error) {
+	client, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.QueryStats(ctx, &statscmd.QueryStatsRequest{
+		Pattern: pattern,
+		Reset_:  reset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("QueryStats failed: %v", err)
+	}
+
+	stats := &Stats{
+		Inbounds:  make(map[string]TrafficPair),
+		Outbounds: make(map[string]TrafficPair),
+		Users:     make(map[string]TrafficPair),
+	}
+	for _, stat := range resp.GetStat() {
+		applyStat(stats, stat.GetName(), stat.GetValue())
+	}
+	return stats, nil
+}
+
+// GetSysStats fetches XRAY's runtime statistics (goroutines, memory, uptime).
+func (c *XRAYClient) GetSysStats(ctx context.Context) (SysStats, error) {
+	client, err := c.dial(ctx)
+	if err != nil {
+		return SysStats{}, err
+	}
+
+	resp, err := client.GetSysStats(ctx, &statscmd.SysStatsRequest{})
+	if err != nil {
+		return SysStats{}, fmt.Errorf("GetSysStats failed: %v", err)
+	}
+
+	return SysStats{
+		Uptime:       resp.GetUptime(),
+		NumGoroutine: resp.GetNumGoroutine(),
+		Alloc:        resp.GetAlloc(),
+		TotalAlloc:   resp.GetTotalAlloc(),
+	}, nil
+}
+
+// applyStat parses a single stat name of the form "inbound>>>TAG>>>traffic>>>uplink",
+// "outbound>>>TAG>>>traffic>>>downlink", or "user>>>EMAIL>>>traffic>>>uplink" and
+// folds its value into the matching bucket in stats.
+func applyStat(stats *Stats, name string, value int64) {
+	parts := strings.Split(name, ">>>")
+	if len(parts) != 4 || parts[2] != "traffic" {
+		return
+	}
+
+	kind, tag, direction := parts[0], parts[1], parts[3]
+
+	var bucket map[string]TrafficPair
+	switch kind {
+	case "inbound":
+		bucket = stats.Inbounds
+	case "outbound":
+		bucket = stats.Outbounds
+	case "user":
+		bucket = stats.Users
+	default:
+		return
+	}
+
+	pair := bucket[tag]
+	switch direction {
+	case "uplink":
+		pair.Uplink = value
+	case "downlink":
+		pair.Downlink = value
+	default:
+		return
+	}
+	bucket[tag] = pair
+}
+
+// defaultXRAYClient is the process-wide client used by getXRAYStats.
+var defaultXRAYClient = NewXRAYClient()
+
+// getXRAYStats retrieves connection statistics from XRAY's StatsService with the same
+// resetting read the periodic monitor tick makes through queryXRAYStats. Traffic
+// counters are reset on every call, so the numbers reported are the delta since the
+// previous reset. Ad hoc callers (e.g. the /stats
+// command) must use getXRAYStatsSnapshot instead, or they will steal the next tick's
+// delta out from under it.
+func getXRAYStats(ctx context.Context) (*ConnectionInfo, error) {
+	stats, err := queryXRAYStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return aggregateConnectionInfo(stats), nil
+}
+
+// getXRAYStatsSnapshot performs a non-destructive read (reset=false), safe for ad hoc
+// queries like the /stats command: it reports the running totals since the last reset
+// without zeroing them, so it can't race the periodic monitor tick's delta.
+func getXRAYStatsSnapshot(ctx context.Context) (*ConnectionInfo, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	stats, err := defaultXRAYClient.QueryStats(dialCtx, "", false)
+	if err != nil {
+		return nil, err
+	}
+	return aggregateConnectionInfo(stats), nil
+}
+
+// queryXRAYStats performs a single reset QueryStats call against the default client.
+func queryXRAYStats(ctx context.Context) (*Stats, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	return defaultXRAYClient.QueryStats(dialCtx, "", true)
+}
+
+// aggregateConnectionInfo collapses a per-inbound Stats snapshot into the totals used
+// for alert messages. ActiveConnections is a proxy — the count of inbounds that saw
+// nonzero traffic this period — not a true concurrent-connection count, since XRAY's
+// stats API doesn't expose one.
+func aggregateConnectionInfo(stats *Stats) *ConnectionInfo {
+	info := &ConnectionInfo{}
+	active := int64(0)
+	for _, pair := range stats.Inbounds {
+		info.UploadTraffic += pair.Uplink
+		info.DownloadTraffic += pair.Downlink
+		if pair.Uplink != 0 || pair.Downlink != 0 {
+			active++
+		}
+	}
+	info.ActiveConnections = active
+	info.TotalTraffic = info.UploadTraffic + info.DownloadTraffic
+	return info
+}
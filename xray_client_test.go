@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestApplyStat(t *testing.T) {
+	stats := &Stats{
+		Inbounds:  make(map[string]TrafficPair),
+		Outbounds: make(map[string]TrafficPair),
+		Users:     make(map[string]TrafficPair),
+	}
+
+	applyStat(stats, "inbound>>>proxy>>>traffic>>>uplink", 100)
+	applyStat(stats, "inbound>>>proxy>>>traffic>>>downlink", 200)
+	applyStat(stats, "outbound>>>direct>>>traffic>>>uplink", 10)
+	applyStat(stats, "user>>>alice@example.com>>>traffic>>>downlink", 50)
+
+	// Malformed or unrecognized names are ignored rather than panicking.
+	applyStat(stats, "inbound>>>proxy>>>traffic", 999)
+	applyStat(stats, "inbound>>>proxy>>>not-traffic>>>uplink", 999)
+	applyStat(stats, "session>>>proxy>>>traffic>>>uplink", 999)
+	applyStat(stats, "inbound>>>proxy>>>traffic>>>sideways", 999)
+
+	if got := stats.Inbounds["proxy"]; got != (TrafficPair{Uplink: 100, Downlink: 200}) {
+		t.Errorf("Inbounds[proxy] = %+v, want {100 200}", got)
+	}
+	if got := stats.Outbounds["direct"]; got != (TrafficPair{Uplink: 10}) {
+		t.Errorf("Outbounds[direct] = %+v, want {10 0}", got)
+	}
+	if got := stats.Users["alice@example.com"]; got != (TrafficPair{Downlink: 50}) {
+		t.Errorf("Users[alice@example.com] = %+v, want {0 50}", got)
+	}
+}
+
+func TestAggregateConnectionInfo(t *testing.T) {
+	stats := &Stats{
+		Inbounds: map[string]TrafficPair{
+			"proxy":  {Uplink: 100, Downlink: 200},
+			"idle":   {},
+			"second": {Uplink: 0, Downlink: 5},
+		},
+	}
+
+	info := aggregateConnectionInfo(stats)
+
+	if info.ActiveConnections != 2 {
+		t.Errorf("ActiveConnections = %d, want 2", info.ActiveConnections)
+	}
+	if info.UploadTraffic != 100 {
+		t.Errorf("UploadTraffic = %d, want 100", info.UploadTraffic)
+	}
+	if info.DownloadTraffic != 205 {
+		t.Errorf("DownloadTraffic = %d, want 205", info.DownloadTraffic)
+	}
+	if info.TotalTraffic != 305 {
+		t.Errorf("TotalTraffic = %d, want 305", info.TotalTraffic)
+	}
+}